@@ -0,0 +1,279 @@
+/*
+Copyright 2013 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package cms implements a small Count-Min Sketch with 4-bit saturating
+// counters, plus a TinyLFU-style doorkeeper admission filter built on top
+// of it (see Admission), to estimate how often a key has recently been
+// seen without keeping an exact per-key count.
+// cms 实现了一个小型的Count-Min Sketch，使用4bit饱和计数器估算某个key
+// 最近被访问的频率，并在此基础上实现了TinyLFU风格的doorkeeper准入过滤器
+// (Admission)，用于替代"随机镜像"式的hotCache准入策略
+package cms
+
+import (
+	"hash/fnv"
+	"strconv"
+	"sync"
+)
+
+const depth = 4 // 固定4行，对应4个相互独立的hash函数
+
+// Sketch is a Count-Min Sketch of 4-bit saturating counters, arranged as
+// depth independent rows of width counters each. It estimates the
+// frequency of a key as the minimum counter value across all rows, which
+// never underestimates the true count and only overestimates due to hash
+// collisions. Sketch is safe for concurrent use.
+// Sketch并发访问是安全的，内部用mu保护计数器
+type Sketch struct {
+	mu sync.Mutex
+
+	width      uint32
+	rows       [depth][]uint8 // 每行width个4bit计数器，两两打包进一个byte
+	additions  uint64         // 自上次halving以来累计的Increment次数
+	decayEvery uint64         // 每decayEvery次Increment触发一次halving，0表示从不自动halving
+}
+
+// New creates a Sketch with the given width (counters per row) and a
+// decay interval: every decayEvery calls to Increment, every counter is
+// halved so the sketch tracks *recent* frequency instead of accumulating
+// forever. decayEvery <= 0 disables automatic decay.
+func New(width int, decayEvery uint64) *Sketch {
+	if width <= 0 {
+		width = 1 << 17
+	}
+	s := &Sketch{width: uint32(width), decayEvery: decayEvery}
+	for i := range s.rows {
+		s.rows[i] = make([]uint8, (width+1)/2)
+	}
+	return s
+}
+
+// Increment records one more observation of key, saturating each row's
+// counter at 15 instead of wrapping around.
+func (s *Sketch) Increment(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for row := 0; row < depth; row++ {
+		idx := s.index(row, key)
+		s.incrementNibble(row, idx)
+	}
+	s.additions++
+	if s.decayEvery > 0 && s.additions >= s.decayEvery {
+		s.halveLocked()
+		s.additions = 0
+	}
+}
+
+// Estimate returns the estimated number of recent observations of key,
+// i.e. the minimum counter across all rows.
+func (s *Sketch) Estimate(key string) uint8 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	min := uint8(15)
+	for row := 0; row < depth; row++ {
+		idx := s.index(row, key)
+		if v := s.nibble(row, idx); v < min {
+			min = v
+		}
+	}
+	return min
+}
+
+// Reset halves every counter, same as the periodic decay Increment
+// triggers automatically every decayEvery calls.
+func (s *Sketch) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.halveLocked()
+	s.additions = 0
+}
+
+func (s *Sketch) halveLocked() {
+	for row := 0; row < depth; row++ {
+		r := s.rows[row]
+		for i, b := range r {
+			// 高低两个nibble分别右移，不能对整个byte右移——会让高位
+			// nibble的最低位溢出到低位nibble里
+			low := (b & 0x0f) >> 1
+			high := (b >> 4) >> 1
+			r[i] = low | (high << 4)
+		}
+	}
+}
+
+// index 用行号作为hash的一部分，让4行互相独立，避免共用同一个hash函数
+// 导致不同行在同一个key上发生相关联的碰撞
+func (s *Sketch) index(row int, key string) uint32 {
+	h := fnv.New32a()
+	h.Write([]byte(strconv.Itoa(row)))
+	h.Write([]byte(key))
+	return h.Sum32() % s.width
+}
+
+func (s *Sketch) nibble(row int, idx uint32) uint8 {
+	b := s.rows[row][idx/2]
+	if idx%2 == 0 {
+		return b & 0x0f
+	}
+	return b >> 4
+}
+
+func (s *Sketch) incrementNibble(row int, idx uint32) {
+	bi := idx / 2
+	b := s.rows[row][bi]
+	if idx%2 == 0 {
+		if v := b & 0x0f; v < 15 {
+			s.rows[row][bi] = b + 1
+		}
+		return
+	}
+	if v := b >> 4; v < 15 {
+		s.rows[row][bi] = b + 0x10
+	}
+}
+
+// doorkeeper is a small first-seen filter sitting in front of a Sketch: a
+// key has to show up twice before it ever touches the (more expensive,
+// more accurate) Count-Min Sketch. This is the actual "doorkeeper" half
+// of a TinyLFU-style admission filter — without it, every one-off key a
+// peer fetches would bump the sketch just as much as a genuinely hot key,
+// which is exactly what Allow is supposed to prevent.
+// doorkeeper是Sketch前面的一道"先见过一次再说"的过滤器：一个key必须被
+// 看到两次才会真的进入(更贵、更精确的)Count-Min Sketch计数。这才是TinyLFU
+// 准入过滤器里真正的"doorkeeper"部分——没有它，每个只出现一次的key都会
+// 像真正的热点key一样拉高sketch计数，而这正是Allow本应避免的事
+type doorkeeper struct {
+	mu    sync.Mutex
+	bits  []uint64
+	width uint32
+}
+
+func newDoorkeeper(width int) *doorkeeper {
+	if width <= 0 {
+		width = 1 << 17
+	}
+	return &doorkeeper{bits: make([]uint64, (width+63)/64), width: uint32(width)}
+}
+
+// testAndSet报告key是否已经在doorkeeper里见过，如果没见过，则顺带记上。
+func (d *doorkeeper) testAndSet(key string) (alreadySeen bool) {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	idx := h.Sum32() % d.width
+	word, bit := idx/64, uint(idx%64)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.bits[word]&(1<<bit) != 0 {
+		return true
+	}
+	d.bits[word] |= 1 << bit
+	return false
+}
+
+func (d *doorkeeper) reset() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for i := range d.bits {
+		d.bits[i] = 0
+	}
+}
+
+// Admission wraps a Sketch with a TinyLFU-style doorkeeper decision: a
+// candidate key is only let in over a victim if it has been observed more
+// often recently, and a key seen for the first time ever never beats a
+// victim that's already been admitted once (see doorkeeper). This is what
+// turns a hotCache from "mirror whatever comes back from a remote peer"
+// into "only mirror keys that are actually hot", without hotCache having
+// to keep its own frequency tracking.
+// Admission包装了一个Sketch和一个doorkeeper，实现TinyLFU风格的准入判断：
+// 候选key第一次出现时只记录进doorkeeper，不计入sketch也几乎不可能顶替
+// victim；只有再次出现时才会真正递增sketch计数，并且只有估计频率比victim
+// （当前hotCache里频率最低的那个key）更高，才允许候选key顶替进hotCache，
+// 否则拒绝，避免一次性的key把真正的热点key挤出去
+type Admission struct {
+	sketch     *Sketch
+	doorkeeper *doorkeeper
+
+	mu         sync.Mutex
+	accepted   uint64
+	rejected   uint64
+	decayHits  uint64
+	decayEvery uint64
+}
+
+// NewAdmission creates an Admission filter backed by a Sketch of the
+// given width and decay interval. The doorkeeper shares the same width
+// and decay cadence as the sketch, so one-off keys get forgotten at
+// roughly the same rate the sketch forgets old frequency counts.
+func NewAdmission(width int, decayEvery uint64) *Admission {
+	return &Admission{
+		sketch:     New(width, decayEvery),
+		doorkeeper: newDoorkeeper(width),
+		decayEvery: decayEvery,
+	}
+}
+
+// Allow reports whether key should be admitted to hotCache in place of
+// victimFreq, the estimated frequency of the key currently occupying the
+// slot it would take. slotEmpty must be true when there is no victim at
+// all (hotCache holds no entries yet) — callers must not approximate this by
+// passing victimFreq 0, since an estimated frequency of 0 for a real
+// victim is a different thing from there being no victim to begin with.
+//
+// A never-before-seen key is only recorded in the doorkeeper — not the
+// sketch — and treated as frequency 0, so it is admitted only when
+// slotEmpty is true and never displaces an already-admitted key; once it
+// has been seen at least twice it starts incrementing the real
+// Count-Min Sketch and gets to compete on its estimated frequency like
+// anyone else.
+func (a *Admission) Allow(key string, victimFreq uint8, slotEmpty bool) bool {
+	var freq uint8
+	if a.doorkeeper.testAndSet(key) {
+		a.sketch.Increment(key)
+		freq = a.sketch.Estimate(key)
+	}
+
+	admit := slotEmpty || freq > victimFreq
+	a.mu.Lock()
+	if admit {
+		a.accepted++
+	} else {
+		a.rejected++
+	}
+	a.decayHits++
+	if a.decayEvery > 0 && a.decayHits >= a.decayEvery {
+		a.doorkeeper.reset()
+		a.decayHits = 0
+	}
+	a.mu.Unlock()
+	return admit
+}
+
+// Estimate returns the current estimated frequency of key without
+// recording a new observation.
+func (a *Admission) Estimate(key string) uint8 {
+	return a.sketch.Estimate(key)
+}
+
+// Stats returns the running totals of accepted and rejected admission
+// decisions, so operators can tell whether the hot cache is actually
+// filtering one-hit-wonders or letting almost everything through.
+func (a *Admission) Stats() (accepted, rejected uint64) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.accepted, a.rejected
+}
@@ -58,12 +58,24 @@ func (m *Map) IsEmpty() bool {
 	return len(m.keys) == 0
 }
 
-// Add adds some keys to the hash.
-// 向环上增加节点
+// Add adds some keys to the hash, each getting the default weight of 1.
+// 向环上增加节点，每个节点使用默认权重1
 func (m *Map) Add(keys ...string) {
+	m.AddWeighted(1, keys...)
+}
+
+// AddWeighted adds some keys to the hash, giving each of them weight times
+// the usual number of virtual nodes. A node with weight 2 ends up with
+// twice as many virtual nodes on the ring as a weight-1 node, and therefore
+// receives roughly twice the share of keys — useful when peers have
+// heterogeneous capacity (bigger boxes, more RAM).
+// 向环上增加节点，每个节点的虚拟节点数量是weight倍，用于处理节点配置不一致的场景，
+// 权重越大，分摊到的key也越多
+func (m *Map) AddWeighted(weight int, keys ...string) {
 	for _, key := range keys {
-		// 生产副本
-		for i := 0; i < m.replicas; i++ {
+		// 生产副本，权重越大，副本越多，在环上分布越密集
+		replicas := m.replicas * weight
+		for i := 0; i < replicas; i++ {
 			hash := int(m.hash([]byte(strconv.Itoa(i) + key)))
 			m.keys = append(m.keys, hash)
 			m.hashMap[hash] = key
@@ -73,6 +85,23 @@ func (m *Map) Add(keys ...string) {
 	sort.Ints(m.keys)
 }
 
+// Remove removes a node and all of its virtual nodes from the hash.
+// 从环上移除一个节点及其所有虚拟节点
+func (m *Map) Remove(key string) {
+	for hash, k := range m.hashMap {
+		if k == key {
+			delete(m.hashMap, hash)
+		}
+	}
+	kept := m.keys[:0]
+	for _, hash := range m.keys {
+		if _, ok := m.hashMap[hash]; ok {
+			kept = append(kept, hash)
+		}
+	}
+	m.keys = kept
+}
+
 // Get gets the closest item in the hash to the provided key.
 func (m *Map) Get(key string) string {
 	if m.IsEmpty() {
@@ -0,0 +1,84 @@
+/*
+Copyright 2013 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package consistenthash
+
+import (
+	"fmt"
+	"testing"
+)
+
+// TestGetDistribution 验证权重相等的节点拿到的负载大致均匀。
+func TestGetDistribution(t *testing.T) {
+	m := New(200, nil)
+	m.Add("a", "b", "c")
+
+	const numKeys = 100000
+	counts := map[string]int{}
+	for i := 0; i < numKeys; i++ {
+		key := fmt.Sprintf("key-%d", i)
+		counts[m.Get(key)]++
+	}
+
+	want := numKeys / 3
+	for _, node := range []string{"a", "b", "c"} {
+		got := counts[node]
+		if deviation := pctDeviation(got, want); deviation > 5.0 {
+			t.Errorf("node %q got %d keys (%.2f%% off %d expected), want within 5%%", node, got, deviation, want)
+		}
+	}
+}
+
+// TestAddWeightedDistribution 验证AddWeighted能让负载按权重的比例分摊，
+// 而不是不论权重都平均分摊。
+func TestAddWeightedDistribution(t *testing.T) {
+	m := New(500, nil)
+	m.AddWeighted(1, "a")
+	m.AddWeighted(2, "b")
+	m.AddWeighted(3, "c")
+
+	const numKeys = 120000
+	counts := map[string]int{}
+	for i := 0; i < numKeys; i++ {
+		key := fmt.Sprintf("key-%d", i)
+		counts[m.Get(key)]++
+	}
+
+	// 权重1+2+3=6份，a应该拿到1/6，b拿到2/6，c拿到3/6
+	wants := map[string]int{
+		"a": numKeys * 1 / 6,
+		"b": numKeys * 2 / 6,
+		"c": numKeys * 3 / 6,
+	}
+	for node, want := range wants {
+		got := counts[node]
+		if deviation := pctDeviation(got, want); deviation > 5.0 {
+			t.Errorf("node %q got %d keys (%.2f%% off %d expected for its weight), want within 5%%", node, got, deviation, want)
+		}
+	}
+}
+
+// pctDeviation 返回got相对want的偏离百分比。
+func pctDeviation(got, want int) float64 {
+	if want == 0 {
+		return 0
+	}
+	diff := got - want
+	if diff < 0 {
+		diff = -diff
+	}
+	return float64(diff) / float64(want) * 100
+}
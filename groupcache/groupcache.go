@@ -0,0 +1,574 @@
+/*
+Copyright 2012 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package groupcache provides a data loading mechanism with caching
+// and de-duplication that works across a set of peer processes.
+//
+// Each data Get first consults a local cache, falls through to other
+// peers' caches if the data isn't found locally, then finally falls
+// through to the original data source if no peer has it.
+// groupcache本体：Group把本地缓存(mainCache)、peer间的hotCache镜像和
+// singleflight去重粘在一起，http.go/grpc.go只是它的两种传输层实现
+package groupcache
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/golang/groupcache/cms"
+	pb "github.com/golang/groupcache/groupcachepb"
+	"github.com/golang/groupcache/lru"
+	"github.com/golang/groupcache/singleflight"
+)
+
+// A Getter loads data for a key.
+type Getter interface {
+	// Get returns the value identified by key, populating dest.
+	//
+	// The returned data must be unversioned. That is, key must
+	// uniquely describe the loaded data, without an implicit
+	// current time, and without relying on cache expiration
+	// mechanisms.
+	Get(ctx context.Context, key string, dest Sink) error
+}
+
+// A GetterFunc implements Getter with a function.
+type GetterFunc func(ctx context.Context, key string, dest Sink) error
+
+func (f GetterFunc) Get(ctx context.Context, key string, dest Sink) error {
+	return f(ctx, key, dest)
+}
+
+var (
+	mu     sync.RWMutex
+	groups = make(map[string]*Group)
+)
+
+// GroupOption configures optional parameters for NewGroup.
+type GroupOption func(*groupOptions)
+
+type groupOptions struct {
+	admissionSketchWidth int
+	admissionDecayEvery  uint64
+}
+
+// AdmissionSketchWidth overrides the number of counters per row in the
+// group's hotCache admission filter (see cms.New). A wider sketch lowers
+// hash-collision overestimation for groups with many distinct hot keys,
+// at the cost of more memory; it defaults to admissionSketchWidth.
+// AdmissionSketchWidth覆盖这个Group的hotCache准入过滤器每行的计数器个数
+// (详见cms.New)。更宽的sketch能降低热点key很多时的哈希碰撞高估，代价是
+// 更多内存；不设置则默认admissionSketchWidth
+func AdmissionSketchWidth(width int) GroupOption {
+	return func(o *groupOptions) { o.admissionSketchWidth = width }
+}
+
+// AdmissionDecayEvery overrides the decay interval of the group's hotCache
+// admission filter: its doorkeeper is cleared every decayEvery admission
+// decisions, and its sketch counters are halved every decayEvery sketch
+// increments (see cms.NewAdmission). It defaults to admissionDecayEvery.
+// AdmissionDecayEvery覆盖这个Group的hotCache准入过滤器的衰减间隔：每做
+// decayEvery次准入决策清空一次doorkeeper，sketch每递增decayEvery次就把
+// 计数器减半(详见cms.NewAdmission)，不设置则默认admissionDecayEvery
+func AdmissionDecayEvery(decayEvery uint64) GroupOption {
+	return func(o *groupOptions) { o.admissionDecayEvery = decayEvery }
+}
+
+// NewGroup creates a coordinated group-aware Getter from a Getter.
+//
+// The returned Getter tries (but does not guarantee) to run only one
+// Get call at once for a given key across an entire set of peer
+// processes. Duplicate calls use the value computed by the first call.
+//
+// The group name must be unique for each getter.
+func NewGroup(name string, cacheBytes int64, getter Getter, opts ...GroupOption) *Group {
+	return newGroup(name, cacheBytes, getter, nil, opts...)
+}
+
+func newGroup(name string, cacheBytes int64, getter Getter, peers PeerPicker, opts ...GroupOption) *Group {
+	if getter == nil {
+		panic("nil Getter")
+	}
+	o := groupOptions{
+		admissionSketchWidth: admissionSketchWidth,
+		admissionDecayEvery:  admissionDecayEvery,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if _, dup := groups[name]; dup {
+		panic("duplicate registration of group " + name)
+	}
+	g := &Group{
+		name:       name,
+		getter:     getter,
+		peers:      peers,
+		cacheBytes: cacheBytes,
+		loadGroup:  &singleflight.Group{},
+	}
+	g.mainCache.init(cacheBytes - cacheBytes/hotCacheRatio)
+	g.hotCache.init(cacheBytes / hotCacheRatio)
+	g.hotAdmission = cms.NewAdmission(o.admissionSketchWidth, o.admissionDecayEvery)
+	groups[name] = g
+	return g
+}
+
+// GetGroup returns the named group previously created with NewGroup, or
+// nil if there's no such group.
+func GetGroup(name string) *Group {
+	mu.RLock()
+	g := groups[name]
+	mu.RUnlock()
+	return g
+}
+
+// flightGroup is the interface Group uses to de-duplicate concurrent
+// Loads for the same key. singleflight.Group implements it; the
+// indirection exists only so tests can substitute their own.
+// 用DoContext而不是Do：调用方的ctx被取消时，load能立刻把ctx.Err()返回
+// 给这一个调用者，而不会被同一个key上其他并发调用者或者fn本身的耗时拖慢；
+// fn依然会在后台跑完，其结果仍会喂给没有取消的等待者
+type flightGroup interface {
+	DoContext(ctx context.Context, key string, fn func() (interface{}, error)) (interface{}, error)
+}
+
+// A Group is a cache namespace and associated data loaded spread over
+// a group of 1 or more machines.
+type Group struct {
+	name       string
+	getter     Getter
+	peersOnce  sync.Once
+	peers      PeerPicker
+	cacheBytes int64 // limit for sum of mainCache and hotCache size
+
+	// mainCache is a cache of the keys for which this process
+	// (amongst its peers) is authoritative. That is, this cache
+	// contains keys whose hash peers.PickPeer(key) chooses this
+	// process.
+	mainCache cache
+
+	// hotCache contains keys/values for which this peer is not
+	// authoritative, but that are popular enough (per hotAdmission)
+	// to warrant mirroring in this process's memory, so we can
+	// avoid going over the network to fetch them from the
+	// authoritative peer.
+	hotCache cache
+
+	// hotAdmission is the TinyLFU-style admission filter deciding
+	// which peer-fetched values are worth mirroring into hotCache;
+	// see Group.load.
+	// hotAdmission是决定"从peer拿到的哪些value值得镜像进hotCache"的
+	// TinyLFU风格准入过滤器，详见Group.load
+	hotAdmission *cms.Admission
+
+	// loadGroup ensures that each key is only fetched once
+	// (either locally or remotely), regardless of the number of
+	// concurrent callers.
+	loadGroup flightGroup
+
+	_ int32 // force Stats to be 8-byte aligned on 32-bit platforms
+
+	// Stats are statistics on the group.
+	Stats Stats
+}
+
+// Stats are per-group statistics.
+type Stats struct {
+	Gets           AtomicInt // any Get request, including from peers
+	CacheHits      AtomicInt // either cache was good
+	PeerLoads      AtomicInt // either remote load or remote cache hit (not an error)
+	PeerErrors     AtomicInt
+	Loads          AtomicInt // (gets - cacheHits)
+	LoadsDeduped   AtomicInt // after singleflight
+	LocalLoads     AtomicInt // total good local loads
+	LocalLoadErrs  AtomicInt // total bad local loads
+	ServerRequests AtomicInt // gets that came over the network from peers
+
+	// HotCacheAdmits/HotCacheRejects count the TinyLFU-style admission
+	// decisions cms.Admission makes about values fetched from a peer;
+	// see Group.load.
+	// HotCacheAdmits/HotCacheRejects统计准入过滤器对"要不要把从peer
+	// 拿到的value镜像进hotCache"做出的决定，详见Group.load
+	HotCacheAdmits  AtomicInt
+	HotCacheRejects AtomicInt
+}
+
+// Name returns the name of the group.
+func (g *Group) Name() string {
+	return g.name
+}
+
+func (g *Group) initPeers() {
+	if g.peers == nil {
+		g.peers = getPeers()
+	}
+}
+
+// Get 从Group里取出key对应的value写入dest：先查本地缓存(main+hot)，
+// 命中则直接返回，否则触发一次load（可能转发给权威peer，也可能落到
+// getter本地计算）
+func (g *Group) Get(ctx context.Context, key string, dest Sink) error {
+	g.peersOnce.Do(g.initPeers)
+	g.Stats.Gets.Add(1)
+	if dest == nil {
+		return errors.New("groupcache: nil dest Sink")
+	}
+	value, ttl, cacheHit := g.lookupCache(key)
+
+	if cacheHit {
+		g.Stats.CacheHits.Add(1)
+		return setSinkView(dest, value, ttl)
+	}
+
+	// Optimization to avoid double unmarshalling or copying: keep
+	// track of whether the dest was already populated. One caller
+	// (us) will set this if so.
+	destPopulated := false
+	value, ttl, destPopulated, err := g.load(ctx, key, dest)
+	if err != nil {
+		return err
+	}
+	if destPopulated {
+		return nil
+	}
+	return setSinkView(dest, value, ttl)
+}
+
+// Remove清除key在本节点(mainCache+hotCache)的副本。它只管本地：跨peer的
+// 扇出由调用方负责，见HTTPPool.broadcast和GRPCPool的等价实现。
+func (g *Group) Remove(ctx context.Context, key string) error {
+	g.peersOnce.Do(g.initPeers)
+	g.mainCache.remove(key)
+	g.hotCache.remove(key)
+	return nil
+}
+
+// SetWithTTL写入一个peer推送过来的value(HTTPPool的PUT handler、
+// GRPCServer.Set)，而不是本节点自己算出value的正常Get路径。ttl<=0表示
+// 永不过期。
+//
+// 只有peers.PickPeer(key)选中本节点时才直接写进mainCache——mainCache的
+// 定义就是"这个进程对其权威的key"，见Group结构体上的注释。对于本节点并不
+// 权威的key，走的是和hotCache.victimFreq/hotAdmission.Allow一样的准入
+// 路径，而不是无条件塞进mainCache：否则每一次广播写都会挤占本不属于这个
+// 节点的mainCache字节预算。
+func (g *Group) SetWithTTL(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	g.peersOnce.Do(g.initPeers)
+	bv := ByteView{b: cloneBytesSlice(value)}
+	if _, ok := g.peers.PickPeer(key); ok {
+		victimFreq, slotEmpty := g.hotCache.victimFreq(g.hotAdmission)
+		if g.hotAdmission.Allow(key, victimFreq, slotEmpty) {
+			g.Stats.HotCacheAdmits.Add(1)
+			g.populateCache(key, bv, ttl, &g.hotCache)
+		} else {
+			g.Stats.HotCacheRejects.Add(1)
+		}
+		return nil
+	}
+	g.populateCache(key, bv, ttl, &g.mainCache)
+	return nil
+}
+
+// loadResult bundles the two values Group.load's singleflight closure
+// needs to hand back through its single interface{} return.
+type loadResult struct {
+	value ByteView
+	ttl   time.Duration
+}
+
+// load loads key either by invoking the getter locally or by sending it
+// to another machine.
+func (g *Group) load(ctx context.Context, key string, dest Sink) (value ByteView, ttl time.Duration, destPopulated bool, err error) {
+	g.Stats.Loads.Add(1)
+	resi, err := g.loadGroup.DoContext(ctx, key, func() (interface{}, error) {
+		// Check the cache again because singleflight can only
+		// de-duplicate calls that overlap: it's possible for
+		// two calls to miss the cache, resulting in two load()
+		// calls, one of which waits inside the singleflight
+		// machinery while the other runs.
+		if value, ttl, cacheHit := g.lookupCache(key); cacheHit {
+			g.Stats.CacheHits.Add(1)
+			return loadResult{value, ttl}, nil
+		}
+		g.Stats.LoadsDeduped.Add(1)
+
+		if peer, ok := g.peers.PickPeer(key); ok {
+			value, ttl, err := g.getFromPeer(ctx, peer, key)
+			if err == nil {
+				g.Stats.PeerLoads.Add(1)
+				// 只有当这个key比hotCache里当前最该被淘汰的那个key更热，
+				// 才把它镜像进hotCache，避免一次性的key把真正的热点挤出去
+				victimFreq, slotEmpty := g.hotCache.victimFreq(g.hotAdmission)
+				if g.hotAdmission.Allow(key, victimFreq, slotEmpty) {
+					g.Stats.HotCacheAdmits.Add(1)
+					g.populateCache(key, value, ttl, &g.hotCache)
+				} else {
+					g.Stats.HotCacheRejects.Add(1)
+				}
+				return loadResult{value, ttl}, nil
+			}
+			g.Stats.PeerErrors.Add(1)
+			// 走到这说明该peer拿取失败，退化为本地计算而不是直接报错，
+			// 因为本地getter很可能本来就知道怎么算这个key
+		}
+
+		value, ttl, err := g.getLocally(ctx, key, dest)
+		if err != nil {
+			g.Stats.LocalLoadErrs.Add(1)
+			return nil, err
+		}
+		g.Stats.LocalLoads.Add(1)
+		destPopulated = true // only one caller of load gets this return value
+		g.populateCache(key, value, ttl, &g.mainCache)
+		return loadResult{value, ttl}, nil
+	})
+	if err == nil {
+		res := resi.(loadResult)
+		value, ttl = res.value, res.ttl
+	}
+	return
+}
+
+// getLocally调用getter算出key对应的value；getter如果把dest断言为
+// TTLSink并调用了SetTTL，这里把那个TTL一并带回去，喂给populateCache，
+// 而不是像过去那样无论getter怎么说都按ttl=0(永不过期)写入mainCache
+func (g *Group) getLocally(ctx context.Context, key string, dest Sink) (ByteView, time.Duration, error) {
+	err := g.getter.Get(ctx, key, dest)
+	if err != nil {
+		return ByteView{}, 0, err
+	}
+	view, err := dest.view()
+	if err != nil {
+		return ByteView{}, 0, err
+	}
+	return view, sinkTTL(dest), nil
+}
+
+// getFromPeer把GetResponse.ttl_nanos一并带回来——这是权威peer在响应里
+// 告诉我们这个value还剩多久过期，populateCache用它决定hotCache里这份
+// 镜像副本何时失效，而不是一律当作永不过期
+func (g *Group) getFromPeer(ctx context.Context, peer ProtoGetter, key string) (ByteView, time.Duration, error) {
+	req := &pb.GetRequest{
+		Group: &g.name,
+		Key:   &key,
+	}
+	res := &pb.GetResponse{}
+	err := peer.Get(ctx, req, res)
+	if err != nil {
+		return ByteView{}, 0, err
+	}
+	return ByteView{b: res.Value}, time.Duration(res.GetTtlNanos()), nil
+}
+
+func (g *Group) lookupCache(key string) (value ByteView, ttl time.Duration, ok bool) {
+	if g.cacheBytes <= 0 {
+		return
+	}
+	value, ttl, ok = g.mainCache.getWithTTL(key)
+	if ok {
+		return
+	}
+	value, ttl, ok = g.hotCache.getWithTTL(key)
+	return
+}
+
+func (g *Group) populateCache(key string, value ByteView, ttl time.Duration, cache *cache) {
+	if g.cacheBytes <= 0 {
+		return
+	}
+	cache.addWithTTL(key, value, ttl)
+}
+
+// CacheType describes a cache of Group's, either the main cache or the
+// hot cache; see Group.CacheStats.
+type CacheType int
+
+const (
+	// MainCache is the cache for items that this peer is the
+	// authoritative owner for.
+	MainCache CacheType = iota + 1
+	// HotCache is the cache for items that seem popular enough to
+	// replicate to this node, even though it's not the authoritative
+	// owner.
+	HotCache
+)
+
+// CacheStats returns stats about the provided cache within the group.
+func (g *Group) CacheStats(which CacheType) CacheStats {
+	switch which {
+	case MainCache:
+		return g.mainCache.stats()
+	case HotCache:
+		return g.hotCache.stats()
+	default:
+		return CacheStats{}
+	}
+}
+
+// hotCacheRatio 决定cacheBytes在两个cache之间的划分：hotCache只拿
+// cacheBytes的1/hotCacheRatio，其余归mainCache，因为hotCache镜像的是这个
+// 节点本不权威的key，给太大预算反而挤占了真正属于本节点的mainCache条目。
+const hotCacheRatio = 8
+
+// admissionSketchWidth/admissionDecayEvery size and age out each Group's
+// hotAdmission filter. 1<<16 counters per row keeps collisions rare for
+// groups with up to a few hundred thousand distinct hot keys; clearing the
+// doorkeeper every 10k admission decisions and halving the sketch every
+// 10k sketch increments keeps the estimate tracking *recent* popularity
+// instead of accumulating forever.
+// admissionSketchWidth/admissionDecayEvery决定每个Group的hotAdmission
+// 过滤器的大小和老化速度：每行1<<16个计数器，对于几十万量级的热点key
+// 碰撞概率仍然较低；每1万次准入决策清空一次doorkeeper、sketch每递增1万次
+// 减半一次，让估计值反映的是"最近"的热度而不是无限累积
+const (
+	admissionSketchWidth = 1 << 16
+	admissionDecayEvery  = 10000
+)
+
+// cache is a wrapper around an *lru.Cache that adds synchronization and
+// makes values always be ByteView. All byte accounting (MaxBytes
+// enforcement, Bytes()) is delegated straight to the embedded
+// lru.Cache — cache itself keeps no parallel nbytes counter to drift out
+// of sync with it.
+// cache是对lru.Cache的并发安全封装：lru.Cache本身明确声明"不是并发安全的"，
+// 这里用一把锁补上，并统计命中率等指标供CacheStats使用；字节数的记账
+// （MaxBytes限制、Bytes()查询）完全交给内部的lru.Cache，cache自己不维护
+// 任何并行的nbytes计数器
+type cache struct {
+	mu         sync.RWMutex
+	lru        *lru.Cache
+	nhit, nget int64
+	nevict     int64 // number of evictions
+}
+
+// init设置这个cache允许占用的最大字节数，0表示不限制（仅受自然内存约束）。
+func (c *cache) init(maxBytes int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.lru = &lru.Cache{
+		MaxBytes: maxBytes,
+		Sizer: func(key lru.Key, value interface{}) int64 {
+			return int64(len(key.(string))) + int64(value.(ByteView).Len())
+		},
+		OnEvicted: func(key lru.Key, value interface{}, size int64) {
+			c.nevict++
+		},
+	}
+}
+
+func (c *cache) stats() CacheStats {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return CacheStats{
+		Bytes:     c.lru.Bytes(),
+		Items:     int64(c.lru.Len()),
+		Gets:      c.nget,
+		Hits:      c.nhit,
+		Evictions: c.nevict,
+	}
+}
+
+func (c *cache) addWithTTL(key string, value ByteView, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.lru.AddWithTTL(key, value, ttl)
+}
+
+func (c *cache) get(key string) (value ByteView, ok bool) {
+	value, _, ok = c.getWithTTL(key)
+	return
+}
+
+// getWithTTL是get的变体，额外返回entry的剩余存活时间（0表示永不过期），
+// 供Group.Get把它转发给调用方，再经由ServeHTTP/GRPCServer.Get转发给其他
+// peer(GetResponse.ttl_nanos)
+func (c *cache) getWithTTL(key string) (value ByteView, ttl time.Duration, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.nget++
+	if c.lru == nil {
+		return
+	}
+	vi, expires, ok := c.lru.GetWithExpiry(key)
+	if !ok {
+		return ByteView{}, 0, false
+	}
+	c.nhit++
+	if !expires.IsZero() {
+		ttl = time.Until(expires)
+	}
+	return vi.(ByteView), ttl, true
+}
+
+func (c *cache) remove(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.lru == nil {
+		return
+	}
+	c.lru.Remove(key)
+}
+
+// victimFreq返回这个cache下一个会被淘汰的key（队尾）在admission里的
+// 估计频率，作为准入新候选key时的比较基准。empty=true表示cache还没有
+// 任何entry可以淘汰（完全是空的），这种情况下没有"victim的估计频率"这个
+// 概念可言——调用方必须依据empty单独放行，而不是把它和某个真实victim
+// 估计频率恰好为0的情况混为一谈，见cms.Admission.Allow
+func (c *cache) victimFreq(admission *cms.Admission) (freq uint8, empty bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.lru == nil {
+		return 0, true
+	}
+	key, ok := c.lru.Oldest()
+	if !ok {
+		return 0, true
+	}
+	return admission.Estimate(key.(string)), false
+}
+
+// CacheStats are returned by Group.CacheStats.
+type CacheStats struct {
+	Bytes     int64
+	Items     int64
+	Gets      int64
+	Hits      int64
+	Evictions int64
+}
+
+// An AtomicInt is an int64 to be accessed atomically.
+type AtomicInt int64
+
+// Add atomically adds n to i.
+func (i *AtomicInt) Add(n int64) {
+	atomic.AddInt64((*int64)(i), n)
+}
+
+// Get atomically gets the value of i.
+func (i *AtomicInt) Get() int64 {
+	return atomic.LoadInt64((*int64)(i))
+}
+
+func (i *AtomicInt) String() string {
+	return strconv.FormatInt(i.Get(), 10)
+}
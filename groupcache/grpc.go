@@ -0,0 +1,279 @@
+/*
+Copyright 2013 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package groupcache
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	pb "github.com/golang/groupcache/groupcachepb"
+	"github.com/golang/protobuf/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// GRPCPool是PeerPicker的另一种实现，承载在持久化的HTTP/2 gRPC连接上，
+// 而不是HTTPPool那样每次请求都走一次net/http+URL拼接。它和HTTPPool复用
+// 同一个PoolCore来管理peer成员和一致性hash选择，区别只在于"怎么把请求
+// 发给选中的peer"。
+// GRPCPool is an alternative PeerPicker backed by a long-lived gRPC
+// connection per peer instead of HTTPPool's per-request net/http calls.
+// It composes the same PoolCore used by HTTPPool so peer membership and
+// consistent-hash selection aren't duplicated.
+type GRPCPool struct {
+	core *PoolCore
+
+	// DialOptions可以让调用方覆盖TLS、拦截器等连接参数。
+	DialOptions []grpc.DialOption
+
+	mu      sync.Mutex // guards getters
+	getters map[string]*grpcGetter // keyed by e.g. "10.0.0.2:8008"
+}
+
+var grpcPoolMade bool
+
+// NewGRPCPool初始化一个gRPC peer池，self是当前节点自己的地址
+// （不含scheme，例如"10.0.0.1:8008"）。
+func NewGRPCPool(self string, replicas int, hashFn func([]byte) uint32) *GRPCPool {
+	if grpcPoolMade {
+		panic("groupcache: NewGRPCPool must be called only once")
+	}
+	grpcPoolMade = true
+
+	if replicas == 0 {
+		replicas = defaultReplicas
+	}
+	p := &GRPCPool{
+		core:    NewPoolCore(self, replicas, hashFn),
+		getters: make(map[string]*grpcGetter),
+	}
+	RegisterPeerPicker(func() PeerPicker { return p })
+	return p
+}
+
+// Set updates the pool's list of peers, establishing a gRPC connection to
+// each one that doesn't already have one.
+func (p *GRPCPool) Set(peers ...string) {
+	p.core.Set(peers...)
+	p.dialPeers(peers)
+}
+
+// SetWeighted is the weighted equivalent of Set; see consistenthash.AddWeighted.
+func (p *GRPCPool) SetWeighted(weights map[string]int) {
+	peers := make([]string, 0, len(weights))
+	for peer := range weights {
+		peers = append(peers, peer)
+	}
+	p.core.SetWeighted(weights)
+	p.dialPeers(peers)
+}
+
+func (p *GRPCPool) dialPeers(peers []string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	getters := make(map[string]*grpcGetter, len(peers))
+	for _, peer := range peers {
+		if g, ok := p.getters[peer]; ok {
+			getters[peer] = g
+			continue
+		}
+		getters[peer] = &grpcGetter{addr: peer, dialOptions: p.DialOptions}
+	}
+	p.getters = getters
+}
+
+// PickPeer选出负责该key的peer对应的gRPC getter。
+func (p *GRPCPool) PickPeer(key string) (ProtoGetter, bool) {
+	peer, ok := p.core.PickPeer(key)
+	if !ok {
+		return nil, false
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	getter, ok := p.getters[peer]
+	return getter, ok
+}
+
+// broadcastRemove把一次DELETE失效扇出给环上除自己以外的每一个peer，和
+// HTTPPool.broadcast的DELETE分支做同一件事：hotCache会把热点key镜像到
+// 任意节点，只让权威节点删除自己的副本是清不干净的。
+func (p *GRPCPool) broadcastRemove(ctx context.Context, groupName, key string) {
+	for _, getter := range p.otherGetters() {
+		getter.Remove(ctx, groupName, key)
+	}
+}
+
+// broadcastSet是broadcastRemove的PUT/Set版本。
+func (p *GRPCPool) broadcastSet(ctx context.Context, groupName, key string, value []byte, ttl time.Duration) {
+	for _, getter := range p.otherGetters() {
+		getter.Set(ctx, groupName, key, value, int64(ttl))
+	}
+}
+
+func (p *GRPCPool) otherGetters() []*grpcGetter {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	getters := make([]*grpcGetter, 0, len(p.getters))
+	for _, peer := range p.core.OtherPeers() {
+		if getter, ok := p.getters[peer]; ok {
+			getters = append(getters, getter)
+		}
+	}
+	return getters
+}
+
+// grpcGetter实现了ProtoGetter，以及Remove/Set失效接口，连接懒建立，
+// 建立后复用同一条HTTP/2流给后续请求，省去了httpGetter每次请求都要
+// 解析URL、新建连接的开销。
+type grpcGetter struct {
+	addr        string
+	dialOptions []grpc.DialOption
+
+	mu   sync.Mutex // guards conn/client
+	conn *grpc.ClientConn
+	// client是生成的pb.GroupCacheClient，承载Get/Remove/Set三个RPC，
+	// 对应新增的失效协议，由groupcachepb/groupcache.proto生成。
+	client pb.GroupCacheClient
+}
+
+func (g *grpcGetter) ensureConn() (pb.GroupCacheClient, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.client != nil {
+		return g.client, nil
+	}
+	opts := g.dialOptions
+	if opts == nil {
+		opts = []grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())}
+	}
+	conn, err := grpc.Dial(g.addr, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("dialing peer %s: %w", g.addr, err)
+	}
+	g.conn = conn
+	g.client = pb.NewGroupCacheClient(conn)
+	return g.client, nil
+}
+
+func (g *grpcGetter) Get(ctx context.Context, in *pb.GetRequest, out *pb.GetResponse) error {
+	client, err := g.ensureConn()
+	if err != nil {
+		return err
+	}
+	resp, err := client.Get(ctx, in)
+	if err != nil {
+		return err
+	}
+	proto.Merge(out, resp)
+	return nil
+}
+
+// relayed始终是true：Remove/Set只会被broadcastRemove/broadcastSet调用
+// 来转发给其他peer，对方的GRPCServer.Remove/Set看到relayed=true就不会
+// 再次广播，否则会在peer之间来回转发形成风暴
+var relayed = true
+
+// Remove让该peer删除自己本地持有的group/key副本，用于跨peer失效广播。
+func (g *grpcGetter) Remove(ctx context.Context, group, key string) error {
+	client, err := g.ensureConn()
+	if err != nil {
+		return err
+	}
+	_, err = client.Remove(ctx, &pb.GetRequest{Group: &group, Key: &key, Relayed: &relayed})
+	return err
+}
+
+// Set让该peer写入/覆盖自己本地持有的group/key副本。
+func (g *grpcGetter) Set(ctx context.Context, group, key string, value []byte, ttlNanos int64) error {
+	client, err := g.ensureConn()
+	if err != nil {
+		return err
+	}
+	_, err = client.Set(ctx, &pb.SetRequest{Group: &group, Key: &key, Value: value, TtlNanos: &ttlNanos, Relayed: &relayed})
+	return err
+}
+
+// GRPCServer实现了pb.GroupCacheServer，是GRPCPool的服务端一半：每个peer
+// 既注册一个GRPCPool作为PeerPicker（客户端角色），也要把GRPCServer挂到自己
+// 的gRPC server上（服务端角色），和HTTPPool.ServeHTTP承担的是同一件事，
+// 只是换了传输协议。pool用于在本地写入生效后，把失效/写入广播给环上的其余
+// peer，和HTTPPool.ServeHTTP里对p.broadcast的调用是同一件事；pool为nil时
+// （例如单机部署）Remove/Set只在本地生效，不会尝试广播。
+type GRPCServer struct {
+	pb.UnimplementedGroupCacheServer
+
+	pool *GRPCPool
+}
+
+// NewGRPCServer creates a GRPCServer whose Remove/Set RPCs broadcast the
+// invalidation/write to every other peer in pool after applying it
+// locally, the gRPC-transport equivalent of HTTPPool.broadcast.
+func NewGRPCServer(pool *GRPCPool) *GRPCServer {
+	return &GRPCServer{pool: pool}
+}
+
+func (s *GRPCServer) Get(ctx context.Context, in *pb.GetRequest) (*pb.GetResponse, error) {
+	group := GetGroup(in.GetGroup())
+	if group == nil {
+		return nil, fmt.Errorf("no such group: %s", in.GetGroup())
+	}
+	group.Stats.ServerRequests.Add(1)
+	var value []byte
+	sink := AllocatingByteSliceSink(&value)
+	if err := group.Get(ctx, in.GetKey(), sink); err != nil {
+		return nil, err
+	}
+	ttlNanos := int64(sinkTTL(sink))
+	return &pb.GetResponse{Value: value, TtlNanos: &ttlNanos}, nil
+}
+
+func (s *GRPCServer) Remove(ctx context.Context, in *pb.GetRequest) (*pb.GetResponse, error) {
+	group := GetGroup(in.GetGroup())
+	if group == nil {
+		return nil, fmt.Errorf("no such group: %s", in.GetGroup())
+	}
+	group.Stats.ServerRequests.Add(1)
+	if err := group.Remove(ctx, in.GetKey()); err != nil {
+		return nil, err
+	}
+	// in.GetRelayed()为true说明这个请求本身就是broadcastRemove转发来的，
+	// 不能再broadcast回去，否则会在peer之间来回转发形成风暴
+	if s.pool != nil && !in.GetRelayed() {
+		s.pool.broadcastRemove(ctx, in.GetGroup(), in.GetKey())
+	}
+	return &pb.GetResponse{}, nil
+}
+
+func (s *GRPCServer) Set(ctx context.Context, in *pb.SetRequest) (*pb.GetResponse, error) {
+	group := GetGroup(in.GetGroup())
+	if group == nil {
+		return nil, fmt.Errorf("no such group: %s", in.GetGroup())
+	}
+	group.Stats.ServerRequests.Add(1)
+	ttl := time.Duration(in.GetTtlNanos())
+	if err := group.SetWithTTL(ctx, in.GetKey(), in.GetValue(), ttl); err != nil {
+		return nil, err
+	}
+	// 同Remove：in.GetRelayed()为true说明这是broadcastSet转发来的，不能
+	// 再次broadcast
+	if s.pool != nil && !in.GetRelayed() {
+		s.pool.broadcastSet(ctx, in.GetGroup(), in.GetKey(), in.GetValue(), ttl)
+	}
+	return &pb.GetResponse{}, nil
+}
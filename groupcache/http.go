@@ -23,14 +23,26 @@ import (
 	"io"
 	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/golang/groupcache/consistenthash"
 	pb "github.com/golang/groupcache/groupcachepb"
 	"github.com/golang/protobuf/proto"
 )
 
+// ttlHeader携带PUT请求写入entry的存活时间，单位为纳秒
+// HTTP传输的PUT请求体是原始value字节，TTL因此用header承载；gRPC传输则用
+// pb.SetRequest的ttl_nanos字段
+const ttlHeader = "X-Groupcache-Ttl"
+
+// relayedHeader标记一个DELETE/PUT请求是broadcast转发给其他peer的，而不是
+// 客户端直接发起的。ServeHTTP看到这个header就不会再次broadcast，否则两个
+// peer会把同一个失效请求来回转发给对方，形成无限风暴（N个peer则是放大风暴）
+const relayedHeader = "X-Groupcache-Relayed"
+
 const defaultBasePath = "/_groupcache/"
 
 const defaultReplicas = 50
@@ -48,14 +60,14 @@ type HTTPPool struct {
 	// If nil, the client uses http.DefaultTransport.
 	Transport func(context.Context) http.RoundTripper
 
-	// this peer's base URL, e.g. "https://example.net:8000"
-	self string
-
 	// opts specifies the options.
 	opts HTTPPoolOptions
 
-	mu          sync.Mutex // guards peers and httpGetters
-	peers       *consistenthash.Map
+	// core owns peers/self and the Set/SetWeighted/PickPeer logic shared
+	// with GRPCPool; HTTPPool only adds the HTTP-specific getters on top.
+	core *PoolCore
+
+	mu          sync.Mutex // guards httpGetters
 	httpGetters map[string]*httpGetter // keyed by e.g. "http://10.0.0.2:8008"
 }
 
@@ -97,7 +109,6 @@ func NewHTTPPoolOpts(self string, o *HTTPPoolOptions) *HTTPPool {
 	httpPoolMade = true
 
 	p := &HTTPPool{
-		self:        self,
 		httpGetters: make(map[string]*httpGetter),
 	}
 	if o != nil {
@@ -109,8 +120,7 @@ func NewHTTPPoolOpts(self string, o *HTTPPoolOptions) *HTTPPool {
 	if p.opts.Replicas == 0 {
 		p.opts.Replicas = defaultReplicas
 	}
-	// 感觉这里没有必要
-	p.peers = consistenthash.New(p.opts.Replicas, p.opts.HashFn)
+	p.core = NewPoolCore(self, p.opts.Replicas, p.opts.HashFn)
 	// 注册全局的peerPicker
 	RegisterPeerPicker(func() PeerPicker { return p })
 	return p
@@ -121,11 +131,11 @@ func NewHTTPPoolOpts(self string, o *HTTPPoolOptions) *HTTPPool {
 // for example "http://example.net:8000".
 // 批量注册peer
 func (p *HTTPPool) Set(peers ...string) {
+	// 初始化一致性hash环,并将peer加入环
+	p.core.Set(peers...)
+
 	p.mu.Lock()
 	defer p.mu.Unlock()
-	// 初始化一致性hash环,并将peer加入环
-	p.peers = consistenthash.New(p.opts.Replicas, p.opts.HashFn)
-	p.peers.Add(peers...)
 	p.httpGetters = make(map[string]*httpGetter, len(peers))
 	// 为每个peer配置一个getter
 	for _, peer := range peers {
@@ -133,17 +143,33 @@ func (p *HTTPPool) Set(peers ...string) {
 	}
 }
 
-// PickPeer 根据key，从一致性hash环中获取对应的peer的getter
-func (p *HTTPPool) PickPeer(key string) (ProtoGetter, bool) {
+// SetWeighted 与Set类似，批量注册peer，但允许为每个peer指定独立的权重，
+// 权重越大，在一致性hash环上分摊到的key越多。例如
+//
+//	pool.SetWeighted(map[string]int{"http://a": 100, "http://b": 200})
+//
+// 使b分到的key数量大致是a的两倍，避免节点配置不均时强行让弱节点和强节点
+// 承担一样的流量
+func (p *HTTPPool) SetWeighted(weights map[string]int) {
+	p.core.SetWeighted(weights)
+
 	p.mu.Lock()
 	defer p.mu.Unlock()
-	if p.peers.IsEmpty() {
-		return nil, false
+	p.httpGetters = make(map[string]*httpGetter, len(weights))
+	for peer := range weights {
+		p.httpGetters[peer] = &httpGetter{transport: p.Transport, baseURL: peer + p.opts.BasePath}
 	}
-	if peer := p.peers.Get(key); peer != p.self {
-		return p.httpGetters[peer], true
+}
+
+// PickPeer 根据key，从一致性hash环中获取对应的peer的getter
+func (p *HTTPPool) PickPeer(key string) (ProtoGetter, bool) {
+	peer, ok := p.core.PickPeer(key)
+	if !ok {
+		return nil, false
 	}
-	return nil, false
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.httpGetters[peer], true
 }
 
 func (p *HTTPPool) ServeHTTP(w http.ResponseWriter, r *http.Request) {
@@ -174,10 +200,50 @@ func (p *HTTPPool) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		ctx = r.Context()
 	}
 
+	// relayed为true说明这个请求是另一个peer的broadcast发来的，而不是
+	// 客户端直接发起的——这种请求只应用本地，绝不能再次broadcast，否则
+	// 两个peer会把同一个失效请求来回转发给对方，形成无限风暴
+	relayed := r.Header.Get(relayedHeader) != ""
+
+	switch r.Method {
+	case http.MethodDelete:
+		// 失效：本地删除后，再向环上除自己以外的其余peer广播同一个DELETE，
+		// 保证mainCache和hotCache在所有节点上的副本都被清理
+		group.Stats.ServerRequests.Add(1)
+		if err := group.Remove(ctx, key); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if !relayed {
+			p.broadcast(ctx, r.Method, groupName, key, nil, 0)
+		}
+		w.WriteHeader(http.StatusNoContent)
+		return
+	case http.MethodPut:
+		// 写入：同DELETE一样，本地写入后再向其余peer广播
+		group.Stats.ServerRequests.Add(1)
+		value, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		ttl := parseTTLHeader(r.Header.Get(ttlHeader))
+		if err := group.SetWithTTL(ctx, key, value, ttl); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if !relayed {
+			p.broadcast(ctx, r.Method, groupName, key, value, ttl)
+		}
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
 	group.Stats.ServerRequests.Add(1)
 	var value []byte
+	sink := AllocatingByteSliceSink(&value)
 	// 检索数据
-	err := group.Get(ctx, key, AllocatingByteSliceSink(&value))
+	err := group.Get(ctx, key, sink)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -185,7 +251,8 @@ func (p *HTTPPool) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 	// 返回数据
 	// Write the value to the response body as a proto message.
-	body, err := proto.Marshal(&pb.GetResponse{Value: value})
+	ttlNanos := int64(sinkTTL(sink))
+	body, err := proto.Marshal(&pb.GetResponse{Value: value, TtlNanos: &ttlNanos})
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -194,6 +261,40 @@ func (p *HTTPPool) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	w.Write(body)
 }
 
+// broadcast 将一次DELETE/PUT失效操作扇出给环上除自己以外的每一个peer，
+// 而不是只发给consistenthash.Map为该key选出的那一个peer：
+// hotCache会把热点key镜像到任意节点，只invalidate权威节点是清不干净的
+func (p *HTTPPool) broadcast(ctx context.Context, method, groupName, key string, value []byte, ttl time.Duration) {
+	p.mu.Lock()
+	getters := make([]*httpGetter, 0, len(p.httpGetters))
+	for _, peer := range p.core.OtherPeers() {
+		if getter, ok := p.httpGetters[peer]; ok {
+			getters = append(getters, getter)
+		}
+	}
+	p.mu.Unlock()
+
+	for _, getter := range getters {
+		switch method {
+		case http.MethodDelete:
+			getter.Remove(ctx, groupName, key)
+		case http.MethodPut:
+			getter.Set(ctx, groupName, key, value, ttl)
+		}
+	}
+}
+
+func parseTTLHeader(s string) time.Duration {
+	if s == "" {
+		return 0
+	}
+	nanos, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0
+	}
+	return time.Duration(nanos)
+}
+
 // http客户端
 type httpGetter struct {
 	transport func(context.Context) http.RoundTripper
@@ -249,3 +350,51 @@ func (h *httpGetter) Get(ctx context.Context, in *pb.GetRequest, out *pb.GetResp
 	}
 	return nil
 }
+
+// Remove 让该peer删除自己本地（mainCache+hotCache）持有的group/key副本
+func (h *httpGetter) Remove(ctx context.Context, group, key string) error {
+	return h.do(ctx, http.MethodDelete, group, key, nil, 0)
+}
+
+// Set 让该peer写入/覆盖自己本地持有的group/key副本，ttl<=0表示不过期
+func (h *httpGetter) Set(ctx context.Context, group, key string, value []byte, ttl time.Duration) error {
+	return h.do(ctx, http.MethodPut, group, key, value, ttl)
+}
+
+func (h *httpGetter) do(ctx context.Context, method, group, key string, value []byte, ttl time.Duration) error {
+	u := fmt.Sprintf(
+		"%v%v/%v",
+		h.baseURL,
+		url.QueryEscape(group),
+		url.QueryEscape(key),
+	)
+	var body io.Reader
+	if value != nil {
+		body = bytes.NewReader(value)
+	}
+	req, err := http.NewRequest(method, u, body)
+	if err != nil {
+		return err
+	}
+	if ttl > 0 {
+		req.Header.Set(ttlHeader, strconv.FormatInt(int64(ttl), 10))
+	}
+	// do只会被Remove/Set调用，而Remove/Set只会被broadcast调用来转发给
+	// 其他peer，所以这里发出去的每一个请求都是relayed的；对方的ServeHTTP
+	// 看到这个header就不会再次broadcast
+	req.Header.Set(relayedHeader, "1")
+	req = req.WithContext(ctx)
+	tr := http.DefaultTransport
+	if h.transport != nil {
+		tr = h.transport(ctx)
+	}
+	res, err := tr.RoundTrip(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK && res.StatusCode != http.StatusNoContent {
+		return fmt.Errorf("server returned: %v", res.Status)
+	}
+	return nil
+}
@@ -17,7 +17,10 @@ limitations under the License.
 // Package lru implements an LRU cache.
 package lru
 
-import "container/list"
+import (
+	"container/list"
+	"time"
+)
 
 // Cache is an LRU cache. It is not safe for concurrent access.
 // LRU cache 并发访问是不安全的
@@ -29,24 +32,56 @@ type Cache struct {
 	// 0代表无限制
 	MaxEntries int
 
+	// MaxBytes is the maximum total size, in bytes, that Add/AddWithTTL
+	// will let the cache grow to before evicting from the tail. Zero means
+	// no byte limit — only MaxEntries (if set) bounds the cache.
+	// 缓存占用字节数的上限，0代表不限制，仅受MaxEntries约束
+	MaxBytes int64
+
+	// Sizer optionally computes the size, in bytes, of a key/value pair
+	// for the purpose of enforcing MaxBytes. If nil, values implementing
+	// Sized are measured via Len(); anything else counts as size 0 and
+	// only ever counts against MaxEntries.
+	// 用于计算entry占用的字节数，不设置则退化为用Sized接口（如果value实现了的话）
+	Sizer func(key Key, value interface{}) int64
+
 	// OnEvicted optionally specifies a callback function to be
-	// executed when an entry is purged from the cache.
-	// 可选，当有entry被淘汰时，执行这个callback
-	OnEvicted func(key Key, value interface{})
+	// executed when an entry is purged from the cache, receiving the
+	// number of bytes (as computed by Sizer/Sized) that were freed.
+	// 可选，当有entry被淘汰时，执行这个callback，size是该entry释放的字节数
+	OnEvicted func(key Key, value interface{}, size int64)
 
 	// 双向链表
 	ll *list.List
 	// map
 	cache map[interface{}]*list.Element
+	// 当前缓存占用的字节数，随Add/removeElement增减
+	nbytes int64
+}
+
+// Sized may be implemented by cache values so Cache can track MaxBytes
+// without callers having to pass a Sizer. This mirrors the Len() pattern
+// groupcache's own ByteView already exposes.
+// value如果实现了Sized，Cache就能在没有显式Sizer的情况下统计字节数
+type Sized interface {
+	Len() int
 }
 
 // A Key may be any value that is comparable. See http://golang.org/ref/spec#Comparison_operators
 type Key interface{}
 
 // 也记录了key, 主要是方便后期淘汰清理map
+// expires为零值表示entry永不过期
 type entry struct {
-	key   Key
-	value interface{}
+	key     Key
+	value   interface{}
+	expires time.Time
+	size    int64 // 该entry在加入时计算出的字节数，淘汰时原样退回给OnEvicted
+}
+
+// expired 判断entry是否已经过期，expires为零值代表没有设置TTL
+func (e *entry) expired(now time.Time) bool {
+	return !e.expires.IsZero() && now.After(e.expires)
 }
 
 // New creates a new Cache.
@@ -63,42 +98,119 @@ func New(maxEntries int) *Cache {
 
 // Add adds a value to the cache.
 func (c *Cache) Add(key Key, value interface{}) {
+	c.add(key, value, time.Time{})
+}
+
+// AddWithTTL adds a value to the cache that expires after ttl has elapsed.
+// A ttl <= 0 means the entry never expires, same as Add.
+// 新增一个会在ttl后过期的entry，ttl<=0表示永不过期，与Add一致
+func (c *Cache) AddWithTTL(key Key, value interface{}, ttl time.Duration) {
+	var expires time.Time
+	if ttl > 0 {
+		expires = time.Now().Add(ttl)
+	}
+	c.add(key, value, expires)
+}
+
+func (c *Cache) add(key Key, value interface{}, expires time.Time) {
 	// 懒初始化,省内存
 	if c.cache == nil {
 		c.cache = make(map[interface{}]*list.Element)
 		c.ll = list.New()
 	}
+	size := c.sizeOf(key, value)
 	if ee, ok := c.cache[key]; ok {
 		// cache hit 就将当前entry移动到队头
 		c.ll.MoveToFront(ee)
-		// 重新赋值
-		ee.Value.(*entry).value = value
-		return
+		// 重新赋值，字节数按新旧差值调整
+		en := ee.Value.(*entry)
+		c.nbytes += size - en.size
+		en.value = value
+		en.expires = expires
+		en.size = size
+	} else {
+		// 未命中，说明不存在，就创建一个entry放入cache中
+		// 根据lru算法，所以新增也需要放到队头
+		ele := c.ll.PushFront(&entry{key: key, value: value, expires: expires, size: size})
+		c.cache[key] = ele
+		c.nbytes += size
 	}
-	// 未命中，说明不存在，就创建一个entry放入cache中
-	// 根据lru算法，所以新增也需要放到队头
-	ele := c.ll.PushFront(&entry{key, value})
-	c.cache[key] = ele
-	// 如果有限制，并且当前容量大于了maxEntries，需要将最近最近未使用的淘汰掉,也就是队尾的元素
-	if c.MaxEntries != 0 && c.ll.Len() > c.MaxEntries {
+	// 如果有限制，并且当前容量/字节数超过了上限，循环淘汰队尾元素直到回到限额内
+	for (c.MaxEntries != 0 && c.ll.Len() > c.MaxEntries) ||
+		(c.MaxBytes != 0 && c.nbytes > c.MaxBytes) {
 		c.RemoveOldest()
 	}
 }
 
+// sizeOf计算一个entry的字节数：优先用Sizer，其次看value是否实现了Sized，
+// 都没有就当作0字节，只受MaxEntries约束
+func (c *Cache) sizeOf(key Key, value interface{}) int64 {
+	if c.Sizer != nil {
+		return c.Sizer(key, value)
+	}
+	if s, ok := value.(Sized); ok {
+		return int64(s.Len())
+	}
+	return 0
+}
+
 // Get looks up a key's value from the cache.
+// 如果entry已经过期，则视为未命中，并顺带将其清除
 func (c *Cache) Get(key Key) (value interface{}, ok bool) {
+	value, _, ok = c.GetWithExpiry(key)
+	return
+}
+
+// GetWithExpiry是Get的变体，额外返回entry的绝对过期时间（零值表示永不
+// 过期），供调用方算出剩余TTL转发给别处（见groupcache.cache.getWithTTL）。
+// 过期判断和Get共用同一个time.Now()快照，而不是分别调用Get和Expires两次
+// ——否则两次调用之间的时间差可能让一个本该算作未过期的entry在第二次
+// 调用时被判定为已过期，返回一个本不该出现的零值TTL（永不过期）
+func (c *Cache) GetWithExpiry(key Key) (value interface{}, expires time.Time, ok bool) {
 	if c.cache == nil {
 		return
 	}
 	if ele, hit := c.cache[key]; hit {
+		en := ele.Value.(*entry)
+		if en.expired(time.Now()) {
+			// 懒惰删除：只有在被访问到时才检查过期时间
+			c.removeElement(ele)
+			return nil, time.Time{}, false
+		}
 		// cache hit
 		// 根据LRU算法，将ele移动到队头
 		c.ll.MoveToFront(ele)
-		return ele.Value.(*entry).value, true
+		return en.value, en.expires, true
 	}
 	return
 }
 
+// DeleteExpired removes every entry whose TTL has elapsed. There is no
+// background goroutine driving this — expired entries otherwise only get
+// reclaimed lazily, the next time Get happens to be called with the same
+// key. Callers that want TTL'd entries reclaimed on an idle cache (no
+// reads at all) must run their own goroutine/ticker that calls
+// DeleteExpired periodically, taking whatever lock they already use
+// around Cache, since Cache itself is not safe for concurrent access.
+// DeleteExpired清除所有已过期的entry。这里没有后台goroutine：过期entry
+// 平时只会在被Get命中到同一个key时才顺带惰性清理掉。如果调用方需要在
+// 缓存完全空闲（没有任何读）的情况下也能按时回收过期entry，必须自己起一个
+// goroutine/ticker定时调用DeleteExpired，并且要像调用Add/Get一样自行加锁，
+// 因为Cache本身并发访问不安全。
+func (c *Cache) DeleteExpired() {
+	if c.cache == nil {
+		return
+	}
+	now := time.Now()
+	for e := c.ll.Front(); e != nil; {
+		next := e.Next()
+		if e.Value.(*entry).expired(now) {
+			c.removeElement(e)
+		}
+		e = next
+	}
+}
+
 // Remove removes the provided key from the cache.
 func (c *Cache) Remove(key Key) {
 	if c.cache == nil {
@@ -110,6 +222,21 @@ func (c *Cache) Remove(key Key) {
 	}
 }
 
+// Oldest returns the key of the item that RemoveOldest would evict next,
+// without removing it. It reports ok=false if the cache is empty.
+// Oldest返回下一个会被RemoveOldest淘汰的key（队尾），但不会真的移除它；
+// 缓存为空时ok=false
+func (c *Cache) Oldest() (key Key, ok bool) {
+	if c.cache == nil {
+		return nil, false
+	}
+	ele := c.ll.Back()
+	if ele == nil {
+		return nil, false
+	}
+	return ele.Value.(*entry).key, true
+}
+
 // RemoveOldest removes the oldest item from the cache.
 // 清除队尾item
 func (c *Cache) RemoveOldest() {
@@ -130,9 +257,10 @@ func (c *Cache) removeElement(e *list.Element) {
 	kv := e.Value.(*entry)
 	// 清除map对应信息
 	delete(c.cache, kv.key)
+	c.nbytes -= kv.size
 	if c.OnEvicted != nil {
-		// 触发callback
-		c.OnEvicted(kv.key, kv.value)
+		// 触发callback，把该entry释放的字节数一并带上，方便上层统计指标
+		c.OnEvicted(kv.key, kv.value, kv.size)
 	}
 }
 
@@ -144,6 +272,13 @@ func (c *Cache) Len() int {
 	return c.ll.Len()
 }
 
+// Bytes returns the total size, in bytes, of all items currently in the
+// cache, as computed by Sizer/Sized when they were added.
+// 返回当前缓存占用的总字节数
+func (c *Cache) Bytes() int64 {
+	return c.nbytes
+}
+
 // Clear purges all stored items from the cache.
 // 清除缓存中的所有item
 func (c *Cache) Clear() {
@@ -151,10 +286,11 @@ func (c *Cache) Clear() {
 		// 触发callback
 		for _, e := range c.cache {
 			kv := e.Value.(*entry)
-			c.OnEvicted(kv.key, kv.value)
+			c.OnEvicted(kv.key, kv.value, kv.size)
 		}
 	}
 	// 直接设置nil就可以，交给gc回收
 	c.ll = nil
 	c.cache = nil
+	c.nbytes = 0
 }
@@ -0,0 +1,73 @@
+/*
+Copyright 2013 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package groupcache
+
+import (
+	"context"
+	"sync"
+
+	pb "github.com/golang/groupcache/groupcachepb"
+)
+
+// ProtoGetter is the interface that must be implemented by a peer.
+// ProtoGetter是访问远程peer的抽象，HTTPPool的httpGetter和GRPCPool的
+// grpcGetter都实现了它，Group不需要关心底层走的是HTTP还是gRPC
+type ProtoGetter interface {
+	Get(ctx context.Context, in *pb.GetRequest, out *pb.GetResponse) error
+}
+
+// PeerPicker is the interface that must be implemented to locate
+// the peer that owns a specific key.
+// PeerPicker根据key选出应该由哪个peer来负责，HTTPPool/GRPCPool都实现了它
+type PeerPicker interface {
+	// PickPeer returns the peer that owns the specific key
+	// and true to indicate that a remote peer was nominated.
+	// It returns nil, false if the key owner is the current peer.
+	PickPeer(key string) (peer ProtoGetter, ok bool)
+}
+
+// NoPeers is an implementation of PeerPicker that never finds a peer.
+type NoPeers struct{}
+
+func (NoPeers) PickPeer(key string) (peer ProtoGetter, ok bool) { return }
+
+var (
+	portPickerMu sync.RWMutex
+	portPicker   func() PeerPicker
+)
+
+// RegisterPeerPicker registers the peer initialization function.
+// It is called once, when the first Group is created.
+// 只能调用一次，再次调用会panic，一个进程只应该运行一种PeerPicker(HTTPPool或GRPCPool)
+func RegisterPeerPicker(fn func() PeerPicker) {
+	portPickerMu.Lock()
+	defer portPickerMu.Unlock()
+	if portPicker != nil {
+		panic("RegisterPeerPicker called more than once")
+	}
+	portPicker = fn
+}
+
+func getPeers() PeerPicker {
+	portPickerMu.RLock()
+	pk := portPicker
+	portPickerMu.RUnlock()
+	if pk == nil {
+		return NoPeers{}
+	}
+	return pk()
+}
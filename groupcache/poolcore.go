@@ -0,0 +1,110 @@
+/*
+Copyright 2013 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package groupcache
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/golang/groupcache/consistenthash"
+)
+
+// PoolCore持有consistenthash.Map、保护它的锁以及self这三样HTTPPool和
+// GRPCPool都需要的状态，抽出来是为了让两种传输复用同一套peer选择/成员管理
+// 逻辑，而不是把Set/PickPeer各写一遍。
+// PoolCore holds the peers map, the mutex guarding it, and self — the state
+// shared by every PeerPicker implementation regardless of transport — so
+// HTTPPool and GRPCPool can compose it instead of duplicating Set/PickPeer.
+type PoolCore struct {
+	self string
+
+	replicas int
+	hashFn   consistenthash.Hash
+
+	mu    sync.Mutex // guards peers and addrs
+	peers *consistenthash.Map
+	addrs []string // 当前注册的peer地址，按字典序排列，便于广播时遍历
+}
+
+// NewPoolCore创建一个空的PoolCore，self是当前节点自己的地址。
+func NewPoolCore(self string, replicas int, hashFn consistenthash.Hash) *PoolCore {
+	return &PoolCore{
+		self:     self,
+		replicas: replicas,
+		hashFn:   hashFn,
+		peers:    consistenthash.New(replicas, hashFn),
+	}
+}
+
+// Set 用给定的peer地址重建一致性hash环，每个peer权重相同。
+func (c *PoolCore) Set(peers ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.peers = consistenthash.New(c.replicas, c.hashFn)
+	c.peers.Add(peers...)
+	c.addrs = append([]string(nil), peers...)
+	sort.Strings(c.addrs)
+}
+
+// SetWeighted 用给定的peer地址和权重重建一致性hash环。
+func (c *PoolCore) SetWeighted(weights map[string]int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.peers = consistenthash.New(c.replicas, c.hashFn)
+	c.addrs = make([]string, 0, len(weights))
+	for peer, weight := range weights {
+		if weight <= 0 {
+			weight = 1
+		}
+		c.peers.AddWeighted(weight, peer)
+		c.addrs = append(c.addrs, peer)
+	}
+	sort.Strings(c.addrs)
+}
+
+// PickPeer 根据key从环上选出权威peer，如果选出的就是自己，则返回ok=false，
+// 表示应该在本地处理。
+func (c *PoolCore) PickPeer(key string) (peer string, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.peers.IsEmpty() {
+		return "", false
+	}
+	if peer := c.peers.Get(key); peer != c.self {
+		return peer, true
+	}
+	return "", false
+}
+
+// OtherPeers 返回除自己以外的全部已注册peer地址，调用方用它来做
+// 失效/写入广播（详见HTTPPool.broadcast）。
+func (c *PoolCore) OtherPeers() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	others := make([]string, 0, len(c.addrs))
+	for _, addr := range c.addrs {
+		if addr != c.self {
+			others = append(others, addr)
+		}
+	}
+	return others
+}
+
+// Self 返回当前节点自己的地址。
+func (c *PoolCore) Self() string {
+	return c.self
+}
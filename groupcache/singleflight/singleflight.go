@@ -19,14 +19,31 @@ limitations under the License.
 // singleflight 提供了防止并发时函数重复调用的机制
 package singleflight
 
-import "sync"
+import (
+	"context"
+	"sync"
+)
 
 // call is an in-flight or completed Do call
 // 表示一个正在进行的do调用或者是已经完成的do调用
 type call struct {
-	wg  sync.WaitGroup // 让其他相同的do调用等待
-	val interface{}    // 存放结果
-	err error          // 存放错误信息
+	val interface{} // 存放结果
+	err error       // 存放错误信息
+
+	// forgotten表示这个call在fn执行完成前就被Forget掉了，doCall完成后
+	// 就不应该再把它从g.m里删掉——那个key可能已经被后来者的新call占用了
+	forgotten bool
+
+	// chans是还在等待这次调用结果的DoChan调用者，fn执行完成后依次写入
+	dups  int
+	chans []chan<- Result
+}
+
+// Result保存一次Do/DoChan调用的结果。
+type Result struct {
+	Val    interface{}
+	Err    error
+	Shared bool // 是否有其他调用者与本次调用共享了结果
 }
 
 // Group represents a class of work and forms a namespace in which
@@ -43,40 +60,75 @@ type Group struct {
 // original to complete and receives the same results.
 // 对于给定的key和function，确保同一时间，只能有一个fn正在执行的
 // 其他重复调用者，需要等待第一个执行的完成，获取第一个执行的结果
-// ps:
-// 同一时间可能会有许多个相同key+fn的do，哪这里就是在保证，只有一个do调用fn
-// 其余的do,等待第一个do的完成，并获取它的结果
 func (g *Group) Do(key string, fn func() (interface{}, error)) (interface{}, error) {
-	// 先获取锁
+	res := <-g.DoChan(key, fn)
+	return res.Val, res.Err
+}
+
+// DoChan与Do类似，但不阻塞调用者：结果通过返回的channel异步投递，
+// channel里只会有一个值，读取后即可丢弃。
+func (g *Group) DoChan(key string, fn func() (interface{}, error)) <-chan Result {
+	ch := make(chan Result, 1)
+
 	g.mu.Lock()
 	if g.m == nil {
 		g.m = make(map[string]*call)
 	}
 	if c, ok := g.m[key]; ok {
-		// 有其他的call正在执行，则释放锁，等待其完成
-		// 这里释放掉锁，原因在于mu是保护m的，你这时候有调用记录,
-		// 并且当前goroutine不需要对m进行写操作，所以可以释放掉锁
+		// 有其他的call正在执行，把自己的channel挂进去等结果就行，
+		// 不需要再起一个goroutine执行fn
+		c.dups++
+		c.chans = append(c.chans, ch)
 		g.mu.Unlock()
-		// 等待结果
-		c.wg.Wait()
-		// 获取结果, 返回
-		return c.val, c.err
+		return ch
 	}
 	// 到这说明没有相同的fn在执行,则创建一个call
-	// 那就在没有释放锁前，先让wg+1并设置调用信息，等释放锁后，好让其他相同的call，进行等待
-	c := new(call)
-	c.wg.Add(1)
+	c := &call{chans: []chan<- Result{ch}}
 	g.m[key] = c
 	g.mu.Unlock() // 对于m的写操作完成了，释放锁
 
-	// 执行fn,保存结果,并通知其他等待的goroutine
+	go g.doCall(c, key, fn)
+	return ch
+}
+
+// doCall执行fn，并把结果广播给所有等待者（Do/DoContext/DoChan调用者
+// 挂进来的channel）。
+func (g *Group) doCall(c *call, key string, fn func() (interface{}, error)) {
 	c.val, c.err = fn()
-	c.wg.Done()
 
-	// 最后删掉，调用信息
 	g.mu.Lock()
-	delete(g.m, key)
+	if !c.forgotten {
+		// 最后删掉，调用信息
+		delete(g.m, key)
+	}
+	for _, ch := range c.chans {
+		ch <- Result{Val: c.val, Err: c.err, Shared: c.dups > 0}
+	}
 	g.mu.Unlock()
+}
+
+// DoContext与Do类似，但会在ctx被取消时立刻给当前调用者返回ctx.Err()，
+// 而不会影响fn本身：fn仍然在后台跑完，其他等待同一个key的调用者照常
+// 拿到它的结果。这避免了一次调用者自己的取消拖慢/卡死所有重复调用者。
+func (g *Group) DoContext(ctx context.Context, key string, fn func() (interface{}, error)) (interface{}, error) {
+	ch := g.DoChan(key, fn)
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case res := <-ch:
+		return res.Val, res.Err
+	}
+}
 
-	return c.val, c.err
+// Forget告诉Group以后不要再记得这个key：下一个到来的调用者会重新执行fn，
+// 而不是等待一个可能已经卡住或者拿到了脏结果的in-flight调用。
+// 正在执行的fn不会被中断，它完成后仍然会把结果投递给在它完成前就已经
+// 注册的等待者。
+func (g *Group) Forget(key string) {
+	g.mu.Lock()
+	if c, ok := g.m[key]; ok {
+		c.forgotten = true
+	}
+	delete(g.m, key)
+	g.mu.Unlock()
 }
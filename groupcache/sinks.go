@@ -0,0 +1,315 @@
+/*
+Copyright 2012 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package groupcache
+
+import (
+	"time"
+
+	"github.com/golang/protobuf/proto"
+)
+
+// A Sink receives data from a Get call.
+// Sink是Get调用的输出目的地，调用方通过它把结果以自己想要的形式取回，
+// 而不用关心底层缓存里存的到底是[]byte、string还是ByteView
+//
+// Implementation of Getter must call exactly one of the Set methods
+// on success.
+type Sink interface {
+	// SetString sets the value to s.
+	SetString(s string) error
+
+	// SetBytes sets the value to the contents of v.
+	// The caller retains ownership of v.
+	SetBytes(v []byte) error
+
+	// SetProto sets the value to the encoded version of m.
+	// The caller retains ownership of m.
+	SetProto(m proto.Message) error
+
+	// view returns a frozen view of the bytes for caching.
+	view() (ByteView, error)
+}
+
+// TTLSink is implemented by every Sink this package returns. A Getter
+// that knows how long the value it just set should live can type-assert
+// dest to TTLSink and call SetTTL after the usual SetString/SetBytes/
+// SetProto call, so that Group.load caches the result with that TTL
+// instead of the default "never expires" — the same TTL a peer's PUT/Set
+// would carry, just sourced from the Getter instead of the wire.
+// TTLSink是本包返回的每个Sink都实现的接口。Getter如果知道自己刚写入的
+// value应该存活多久，可以把dest类型断言为TTLSink，在常规的SetString/
+// SetBytes/SetProto调用之后再调一次SetTTL，Group.load就会按这个TTL缓存
+// 结果，而不是默认的"永不过期"——和peer的PUT/Set带来的TTL是同一个概念，
+// 只是来源换成了Getter
+type TTLSink interface {
+	Sink
+
+	// SetTTL records how long the value already set via SetString/
+	// SetBytes/SetProto should live in the cache. ttl <= 0 means the
+	// value never expires, the same meaning as Group.SetWithTTL's ttl.
+	SetTTL(ttl time.Duration)
+}
+
+// sinkTTL returns the TTL most recently recorded on s via SetTTL, or 0
+// ("never expires") if s was never told one.
+func sinkTTL(s Sink) time.Duration {
+	if ts, ok := s.(interface{ getTTL() time.Duration }); ok {
+		return ts.getTTL()
+	}
+	return 0
+}
+
+func cloneBytesSlice(b []byte) []byte {
+	c := make([]byte, len(b))
+	copy(c, b)
+	return c
+}
+
+// setSinkView 是个内部帮助函数，用来把一个已经算好的ByteView复用到
+// 另一个Sink上，避免populateCache时对同一份数据重复编解码；ttl会通过
+// SetTTL一并记录下来，供调用方后续转发（例如GRPCServer.Get回填
+// GetResponse.ttl_nanos）
+func setSinkView(s Sink, v ByteView, ttl time.Duration) error {
+	var err error
+	if bv, ok := s.(interface{ setView(ByteView) error }); ok {
+		err = bv.setView(v)
+	} else if v.b != nil {
+		err = s.SetBytes(v.b)
+	} else {
+		err = s.SetString(v.s)
+	}
+	if err != nil {
+		return err
+	}
+	if ts, ok := s.(TTLSink); ok {
+		ts.SetTTL(ttl)
+	}
+	return nil
+}
+
+// StringSink returns a Sink that populates the provided string pointer.
+func StringSink(sp *string) Sink {
+	return &stringSink{sp: sp}
+}
+
+type stringSink struct {
+	sp  *string
+	v   ByteView
+	ttl time.Duration
+}
+
+func (s *stringSink) view() (ByteView, error) { return s.v, nil }
+
+func (s *stringSink) SetTTL(ttl time.Duration) { s.ttl = ttl }
+func (s *stringSink) getTTL() time.Duration    { return s.ttl }
+
+func (s *stringSink) SetString(v string) error {
+	s.v.b = nil
+	s.v.s = v
+	*s.sp = v
+	return nil
+}
+
+func (s *stringSink) SetBytes(v []byte) error {
+	return s.SetString(string(v))
+}
+
+func (s *stringSink) SetProto(m proto.Message) error {
+	b, err := proto.Marshal(m)
+	if err != nil {
+		return err
+	}
+	return s.SetBytes(b)
+}
+
+func (s *stringSink) setView(v ByteView) error {
+	s.v = v
+	*s.sp = v.String()
+	return nil
+}
+
+// ByteViewSink returns a Sink that populates a ByteView.
+func ByteViewSink(dst *ByteView) Sink {
+	if dst == nil {
+		panic("nil dst")
+	}
+	return &byteViewSink{dst: dst}
+}
+
+type byteViewSink struct {
+	dst *ByteView
+	ttl time.Duration
+}
+
+func (s *byteViewSink) view() (ByteView, error) { return *s.dst, nil }
+
+func (s *byteViewSink) SetTTL(ttl time.Duration) { s.ttl = ttl }
+func (s *byteViewSink) getTTL() time.Duration    { return s.ttl }
+
+func (s *byteViewSink) setView(v ByteView) error {
+	*s.dst = v
+	return nil
+}
+
+func (s *byteViewSink) SetProto(m proto.Message) error {
+	b, err := proto.Marshal(m)
+	if err != nil {
+		return err
+	}
+	*s.dst = ByteView{b: b}
+	return nil
+}
+
+func (s *byteViewSink) SetBytes(b []byte) error {
+	*s.dst = ByteView{b: cloneBytesSlice(b)}
+	return nil
+}
+
+func (s *byteViewSink) SetString(v string) error {
+	*s.dst = ByteView{s: v}
+	return nil
+}
+
+// ProtoSink returns a sink that unmarshals binary proto values into m.
+func ProtoSink(m proto.Message) Sink {
+	return &protoSink{dst: m}
+}
+
+type protoSink struct {
+	dst proto.Message
+	typ string
+
+	v   ByteView
+	ttl time.Duration
+}
+
+func (s *protoSink) view() (ByteView, error) { return s.v, nil }
+
+func (s *protoSink) SetTTL(ttl time.Duration) { s.ttl = ttl }
+func (s *protoSink) getTTL() time.Duration    { return s.ttl }
+
+func (s *protoSink) setView(v ByteView) error {
+	if err := proto.Unmarshal(v.ByteSlice(), s.dst); err != nil {
+		return err
+	}
+	s.v = v
+	return nil
+}
+
+func (s *protoSink) SetProto(m proto.Message) error {
+	b, err := proto.Marshal(m)
+	if err != nil {
+		return err
+	}
+	if err := proto.Unmarshal(b, s.dst); err != nil {
+		return err
+	}
+	s.v = ByteView{b: b}
+	return nil
+}
+
+func (s *protoSink) SetBytes(b []byte) error {
+	return s.setView(ByteView{b: cloneBytesSlice(b)})
+}
+
+func (s *protoSink) SetString(v string) error {
+	return s.setView(ByteView{s: v})
+}
+
+// AllocatingByteSliceSink returns a Sink that allocates a byte slice to
+// hold the received value and assigns it to *dst.
+func AllocatingByteSliceSink(dst *[]byte) Sink {
+	return &allocBytesSink{dst: dst}
+}
+
+type allocBytesSink struct {
+	dst *[]byte
+	v   ByteView
+	ttl time.Duration
+}
+
+func (s *allocBytesSink) view() (ByteView, error) { return s.v, nil }
+
+func (s *allocBytesSink) SetTTL(ttl time.Duration) { s.ttl = ttl }
+func (s *allocBytesSink) getTTL() time.Duration    { return s.ttl }
+
+func (s *allocBytesSink) setView(v ByteView) error {
+	if v.b != nil {
+		*s.dst = cloneBytesSlice(v.b)
+	} else {
+		*s.dst = []byte(v.s)
+	}
+	s.v = v
+	return nil
+}
+
+func (s *allocBytesSink) SetProto(m proto.Message) error {
+	b, err := proto.Marshal(m)
+	if err != nil {
+		return err
+	}
+	return s.setView(ByteView{b: b})
+}
+
+func (s *allocBytesSink) SetBytes(b []byte) error {
+	return s.setView(ByteView{b: cloneBytesSlice(b)})
+}
+
+func (s *allocBytesSink) SetString(v string) error {
+	return s.setView(ByteView{s: v})
+}
+
+// TruncatingByteSliceSink returns a Sink that writes up to len(*dst)
+// bytes to *dst, silently truncating any extra bytes. The value returned
+// via the source Sink methods is unaffected by the truncation.
+func TruncatingByteSliceSink(dst *[]byte) Sink {
+	return &truncBytesSink{dst: dst}
+}
+
+type truncBytesSink struct {
+	dst *[]byte
+	v   ByteView
+	ttl time.Duration
+}
+
+func (s *truncBytesSink) view() (ByteView, error) { return s.v, nil }
+
+func (s *truncBytesSink) SetTTL(ttl time.Duration) { s.ttl = ttl }
+func (s *truncBytesSink) getTTL() time.Duration    { return s.ttl }
+
+func (s *truncBytesSink) setView(v ByteView) error {
+	n := copy(*s.dst, v.ByteSlice())
+	*s.dst = (*s.dst)[:n]
+	s.v = v
+	return nil
+}
+
+func (s *truncBytesSink) SetProto(m proto.Message) error {
+	b, err := proto.Marshal(m)
+	if err != nil {
+		return err
+	}
+	return s.setView(ByteView{b: b})
+}
+
+func (s *truncBytesSink) SetBytes(b []byte) error {
+	return s.setView(ByteView{b: b})
+}
+
+func (s *truncBytesSink) SetString(v string) error {
+	return s.setView(ByteView{s: v})
+}